@@ -0,0 +1,155 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+
+    "github.com/golang-jwt/jwt/v5"
+)
+
+// Claims are the subset of Supabase GoTrue JWT claims we care about.
+type Claims struct {
+    Subject string `json:"sub"`
+    Email   string `json:"email"`
+    Role    string `json:"role"`
+    jwt.RegisteredClaims
+}
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+var errNoClaims = errors.New("no claims in request context")
+
+// authMiddleware verifies the Supabase-issued JWT on the Authorization header
+// and stashes the parsed claims in the request context for downstream handlers.
+func authMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        header := r.Header.Get("Authorization")
+        tokenString, ok := strings.CutPrefix(header, "Bearer ")
+        if !ok || tokenString == "" {
+            http.Error(w, "Unauthorized", http.StatusUnauthorized)
+            return
+        }
+
+        claims := &Claims{}
+        token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+            if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+                return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+            }
+            return []byte(cfg.JWTSecret), nil
+        }, jwt.WithValidMethods([]string{"HS256"}), jwt.WithAudience("authenticated"), jwt.WithIssuedAt(), jwt.WithExpirationRequired())
+        if err != nil || !token.Valid {
+            http.Error(w, "Unauthorized", http.StatusUnauthorized)
+            return
+        }
+
+        stashUserID(w, claims.Subject)
+
+        ctx := context.WithValue(r.Context(), claimsContextKey, claims)
+        next.ServeHTTP(w, r.WithContext(ctx))
+    })
+}
+
+// getUserIDFromContext returns the tenant user ID from the JWT claims
+// stashed in the request context by authMiddleware.
+func getUserIDFromContext(r *http.Request) (string, error) {
+    claims, ok := r.Context().Value(claimsContextKey).(*Claims)
+    if !ok || claims.Subject == "" {
+        return "", errNoClaims
+    }
+    return claims.Subject, nil
+}
+
+type gotrueTokenResponse struct {
+    AccessToken  string `json:"access_token"`
+    RefreshToken string `json:"refresh_token"`
+    ExpiresIn    int    `json:"expires_in"`
+    TokenType    string `json:"token_type"`
+}
+
+// loginHandler proxies password grants to Supabase's GoTrue REST API and
+// returns the resulting tokens verbatim.
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+    var creds struct {
+        Email    string `json:"email"`
+        Password string `json:"password"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+        http.Error(w, "invalid request body", http.StatusBadRequest)
+        return
+    }
+
+    proxyGoTrueRequest(w, r, "password", map[string]string{
+        "email":    creds.Email,
+        "password": creds.Password,
+    })
+}
+
+// refreshHandler proxies refresh_token grants to Supabase's GoTrue REST API.
+func refreshHandler(w http.ResponseWriter, r *http.Request) {
+    var body struct {
+        RefreshToken string `json:"refresh_token"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+        http.Error(w, "invalid request body", http.StatusBadRequest)
+        return
+    }
+
+    proxyGoTrueRequest(w, r, "refresh_token", map[string]string{
+        "refresh_token": body.RefreshToken,
+    })
+}
+
+// proxyGoTrueRequest forwards a token grant to Supabase's GoTrue endpoint.
+// Non-2xx responses are relayed verbatim; a 2xx response is re-encoded with
+// only access_token, refresh_token, expires_in and token_type, so any other
+// fields GoTrue returns are dropped.
+func proxyGoTrueRequest(w http.ResponseWriter, r *http.Request, grantType string, payload map[string]string) {
+    reqBody, err := json.Marshal(payload)
+    if err != nil {
+        http.Error(w, "internal error", http.StatusInternalServerError)
+        return
+    }
+
+    url := fmt.Sprintf("%s/auth/v1/token?grant_type=%s", cfg.SupabaseURL, grantType)
+    req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, url, strings.NewReader(string(reqBody)))
+    if err != nil {
+        http.Error(w, "internal error", http.StatusInternalServerError)
+        return
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("apikey", cfg.SupabaseAnonKey)
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        http.Error(w, "auth provider unreachable", http.StatusBadGateway)
+        return
+    }
+    defer resp.Body.Close()
+
+    // Non-2xx responses come back from GoTrue shaped as {"error", "error_description"}
+    // or similar, not gotrueTokenResponse - relay the body verbatim so the
+    // caller sees GoTrue's actual error instead of a generic decode failure.
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+        w.WriteHeader(resp.StatusCode)
+        io.Copy(w, resp.Body)
+        return
+    }
+
+    var tokenResp gotrueTokenResponse
+    if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+        http.Error(w, "auth provider returned an unexpected response", http.StatusBadGateway)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(resp.StatusCode)
+    json.NewEncoder(w).Encode(tokenResp)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/golang-jwt/jwt/v5"
+)
+
+const testJWTSecret = "test-secret"
+
+func signTestToken(t *testing.T, claims jwt.MapClaims, secret string) string {
+    t.Helper()
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+    signed, err := token.SignedString([]byte(secret))
+    if err != nil {
+        t.Fatalf("failed to sign test token: %v", err)
+    }
+    return signed
+}
+
+func TestAuthMiddleware(t *testing.T) {
+    cfg = &Config{JWTSecret: testJWTSecret}
+
+    validClaims := jwt.MapClaims{
+        "sub": "user-1",
+        "aud": "authenticated",
+        "iat": time.Now().Unix(),
+        "exp": time.Now().Add(time.Hour).Unix(),
+    }
+
+    cases := []struct {
+        name       string
+        header     string
+        wantStatus int
+    }{
+        {
+            name:       "missing header",
+            header:     "",
+            wantStatus: http.StatusUnauthorized,
+        },
+        {
+            name:       "garbled header",
+            header:     "not-a-bearer-token",
+            wantStatus: http.StatusUnauthorized,
+        },
+        {
+            name:       "bad signature",
+            header:     "Bearer " + signTestToken(t, validClaims, "wrong-secret"),
+            wantStatus: http.StatusUnauthorized,
+        },
+        {
+            name: "wrong audience",
+            header: "Bearer " + signTestToken(t, jwt.MapClaims{
+                "sub": "user-1",
+                "aud": "not-authenticated",
+                "iat": time.Now().Unix(),
+                "exp": time.Now().Add(time.Hour).Unix(),
+            }, testJWTSecret),
+            wantStatus: http.StatusUnauthorized,
+        },
+        {
+            name: "missing exp",
+            header: "Bearer " + signTestToken(t, jwt.MapClaims{
+                "sub": "user-1",
+                "aud": "authenticated",
+                "iat": time.Now().Unix(),
+            }, testJWTSecret),
+            wantStatus: http.StatusUnauthorized,
+        },
+        {
+            name:       "valid token",
+            header:     "Bearer " + signTestToken(t, validClaims, testJWTSecret),
+            wantStatus: http.StatusOK,
+        },
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            var sawUserID string
+            next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                sawUserID, _ = getUserIDFromContext(r)
+                w.WriteHeader(http.StatusOK)
+            })
+
+            req := httptest.NewRequest(http.MethodGet, "/api/catalogue/tracks", nil)
+            if c.header != "" {
+                req.Header.Set("Authorization", c.header)
+            }
+            rec := httptest.NewRecorder()
+
+            authMiddleware(next).ServeHTTP(rec, req)
+
+            if rec.Code != c.wantStatus {
+                t.Fatalf("status = %d, want %d", rec.Code, c.wantStatus)
+            }
+            if c.wantStatus == http.StatusOK && sawUserID != "user-1" {
+                t.Errorf("expected downstream handler to see user_id %q, got %q", "user-1", sawUserID)
+            }
+        })
+    }
+}
+
+func TestGetUserIDFromContextNoClaims(t *testing.T) {
+    req := httptest.NewRequest(http.MethodGet, "/api/catalogue/tracks", nil)
+    if _, err := getUserIDFromContext(req); err != errNoClaims {
+        t.Errorf("expected errNoClaims, got %v", err)
+    }
+}
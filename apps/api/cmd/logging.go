@@ -0,0 +1,119 @@
+package main
+
+import (
+    "context"
+    "log/slog"
+    "net/http"
+    "time"
+
+    "github.com/gorilla/mux"
+    "github.com/oklog/ulid/v2"
+)
+
+type requestIDKeyType struct{}
+
+var requestIDKey requestIDKeyType
+
+// requestIDMiddleware honors an incoming X-Request-Id or generates a ULID,
+// stashes it in the context so handlers/logging can reference it, and
+// echoes it back on the response.
+func requestIDMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requestID := r.Header.Get("X-Request-Id")
+        if requestID == "" {
+            requestID = ulid.Make().String()
+        }
+        w.Header().Set("X-Request-Id", requestID)
+        ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+        next.ServeHTTP(w, r.WithContext(ctx))
+    })
+}
+
+func requestIDFromContext(ctx context.Context) string {
+    id, _ := ctx.Value(requestIDKey).(string)
+    return id
+}
+
+// responseRecorder tracks the status code and byte count written so the
+// logging middleware can report them after the handler returns. It also
+// carries the authenticated user id: authMiddleware only wraps the
+// protected subrouter, several layers inside loggingMiddleware, and
+// authMiddleware's next.ServeHTTP(w, r.WithContext(ctx)) threads claims
+// through a new *http.Request that loggingMiddleware never sees, so the
+// user id can't be read back via r.Context() once next.ServeHTTP returns.
+// Routing it through this ResponseWriter, which every middleware in the
+// chain shares, is the one object loggingMiddleware can still read it from.
+type responseRecorder struct {
+    http.ResponseWriter
+    status int
+    bytes  int
+    userID string
+}
+
+// stashUserID records id against w's responseRecorder, if any, so
+// loggingMiddleware can log it (see the responseRecorder doc comment).
+func stashUserID(w http.ResponseWriter, id string) {
+    if rr, ok := w.(*responseRecorder); ok {
+        rr.userID = id
+    }
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+    rr.status = status
+    rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+    if rr.status == 0 {
+        rr.status = http.StatusOK
+    }
+    n, err := rr.ResponseWriter.Write(b)
+    rr.bytes += n
+    return n, err
+}
+
+// Flush delegates to the underlying http.Flusher, if any, so streaming
+// handlers (e.g. the bulk NDJSON ingest) still flush incrementally through
+// this wrapper.
+func (rr *responseRecorder) Flush() {
+    if flusher, ok := rr.ResponseWriter.(http.Flusher); ok {
+        flusher.Flush()
+    }
+}
+
+// Unwrap exposes the underlying ResponseWriter so http.ResponseController
+// (used by handlerutil.ExemptWriteTimeout) can drill through this wrapper.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+    return rr.ResponseWriter
+}
+
+// loggingMiddleware emits one structured JSON line per request via log/slog,
+// and records http_requests_total/http_request_duration_seconds for /metrics.
+func loggingMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        start := time.Now()
+        rr := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+        next.ServeHTTP(rr, r)
+
+        duration := time.Since(start)
+        route := "unknown"
+        if match := mux.CurrentRoute(r); match != nil {
+            if tmpl, err := match.GetPathTemplate(); err == nil {
+                route = tmpl
+            }
+        }
+        slog.Info("request",
+            "method", r.Method,
+            "path", route,
+            "status", rr.status,
+            "bytes", rr.bytes,
+            "duration_ms", duration.Milliseconds(),
+            "user_id", rr.userID,
+            "request_id", requestIDFromContext(r.Context()),
+        )
+
+        httpRequestsTotal.WithLabelValues(route, r.Method, http.StatusText(rr.status)).Inc()
+        httpRequestDuration.WithLabelValues(route, r.Method).Observe(duration.Seconds())
+    })
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+    "context"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/golang-jwt/jwt/v5"
+    "github.com/gorilla/mux"
+)
+
+func TestRequestIDMiddlewareHonorsIncomingHeader(t *testing.T) {
+    var gotID string
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        gotID = requestIDFromContext(r.Context())
+    })
+
+    req := httptest.NewRequest(http.MethodGet, "/", nil)
+    req.Header.Set("X-Request-Id", "client-supplied-id")
+    rec := httptest.NewRecorder()
+
+    requestIDMiddleware(next).ServeHTTP(rec, req)
+
+    if gotID != "client-supplied-id" {
+        t.Errorf("context request id = %q, want %q", gotID, "client-supplied-id")
+    }
+    if got := rec.Header().Get("X-Request-Id"); got != "client-supplied-id" {
+        t.Errorf("response header = %q, want %q", got, "client-supplied-id")
+    }
+}
+
+func TestRequestIDMiddlewareGeneratesIDWhenMissing(t *testing.T) {
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+    rec := httptest.NewRecorder()
+    requestIDMiddleware(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+    if rec.Header().Get("X-Request-Id") == "" {
+        t.Error("expected a generated X-Request-Id header")
+    }
+}
+
+func TestResponseRecorderTracksStatusAndBytes(t *testing.T) {
+    rec := httptest.NewRecorder()
+    rr := &responseRecorder{ResponseWriter: rec, status: http.StatusOK}
+
+    rr.WriteHeader(http.StatusCreated)
+    n, err := rr.Write([]byte("hello"))
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if n != 5 {
+        t.Errorf("Write returned %d, want 5", n)
+    }
+    if rr.status != http.StatusCreated {
+        t.Errorf("status = %d, want %d", rr.status, http.StatusCreated)
+    }
+    if rr.bytes != 5 {
+        t.Errorf("bytes = %d, want 5", rr.bytes)
+    }
+}
+
+func TestResponseRecorderDefaultsStatusToOKOnWrite(t *testing.T) {
+    rec := httptest.NewRecorder()
+    rr := &responseRecorder{ResponseWriter: rec}
+
+    rr.Write([]byte("hi"))
+
+    if rr.status != http.StatusOK {
+        t.Errorf("status = %d, want %d when WriteHeader was never called", rr.status, http.StatusOK)
+    }
+}
+
+func TestResponseRecorderUnwrapExposesUnderlyingWriter(t *testing.T) {
+    rec := httptest.NewRecorder()
+    rr := &responseRecorder{ResponseWriter: rec}
+
+    if rr.Unwrap() != rec {
+        t.Error("Unwrap() did not return the underlying ResponseWriter")
+    }
+}
+
+func TestStashUserIDSetsResponseRecorderField(t *testing.T) {
+    rr := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+
+    stashUserID(rr, "user-1")
+
+    if rr.userID != "user-1" {
+        t.Errorf("userID = %q, want %q", rr.userID, "user-1")
+    }
+}
+
+func TestStashUserIDIgnoresNonRecorderWriter(t *testing.T) {
+    // Should not panic when w isn't a *responseRecorder.
+    stashUserID(httptest.NewRecorder(), "user-1")
+}
+
+// attrCapturingHandler is a minimal slog.Handler that records the last
+// record's attributes so tests can assert on structured log output.
+type attrCapturingHandler struct {
+    attrs map[string]any
+}
+
+func (h *attrCapturingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *attrCapturingHandler) Handle(_ context.Context, r slog.Record) error {
+    h.attrs = make(map[string]any, r.NumAttrs())
+    r.Attrs(func(a slog.Attr) bool {
+        h.attrs[a.Key] = a.Value.Any()
+        return true
+    })
+    return nil
+}
+
+func (h *attrCapturingHandler) WithAttrs(attrs []slog.Attr) slog.Handler { return h }
+func (h *attrCapturingHandler) WithGroup(name string) slog.Handler       { return h }
+
+// TestLoggingMiddlewareLogsAuthenticatedUserID reproduces main.go's router
+// composition - requestIDMiddleware/loggingMiddleware mounted globally,
+// authMiddleware mounted only on a protected subrouter several layers
+// inside them - to guard against the logging middleware reporting an empty
+// user_id for authenticated requests (authMiddleware's
+// next.ServeHTTP(w, r.WithContext(ctx)) threads claims through a new
+// *http.Request that loggingMiddleware, holding the original request in its
+// closure, never sees).
+func TestLoggingMiddlewareLogsAuthenticatedUserID(t *testing.T) {
+    capture := &attrCapturingHandler{}
+    origLogger := slog.Default()
+    slog.SetDefault(slog.New(capture))
+    defer slog.SetDefault(origLogger)
+
+    r := mux.NewRouter()
+    r.Use(requestIDMiddleware)
+    r.Use(loggingMiddleware)
+
+    protected := r.PathPrefix("/api").Subrouter()
+    protected.Use(authMiddleware)
+    protected.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+        userID, _ := getUserIDFromContext(r)
+        w.Write([]byte(userID))
+    }).Methods("GET")
+
+    cfg = &Config{JWTSecret: testJWTSecret}
+    token := signTestToken(t, jwt.MapClaims{
+        "sub": "alice",
+        "aud": "authenticated",
+        "iat": time.Now().Unix(),
+        "exp": time.Now().Add(time.Hour).Unix(),
+    }, testJWTSecret)
+
+    req := httptest.NewRequest(http.MethodGet, "/api/whoami", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+    rec := httptest.NewRecorder()
+
+    r.ServeHTTP(rec, req)
+
+    if rec.Body.String() != "alice" {
+        t.Fatalf("handler saw user id %q, want %q", rec.Body.String(), "alice")
+    }
+    if got := capture.attrs["user_id"]; got != "alice" {
+        t.Errorf("logged user_id = %v, want %q", got, "alice")
+    }
+}
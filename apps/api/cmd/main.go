@@ -12,23 +12,32 @@ import (
     "github.com/jackc/pgx/v5/pgxpool"
     "github.com/rs/cors"
     "github.com/joho/godotenv"
+
+    "github.com/BrowningL/ISRCAnalytics.com/apps/api/internal/handlerutil"
 )
 
 var db *pgxpool.Pool
+var cfg *Config
 
 type Config struct {
-    DatabaseURL string
-    Port        string
-    JWTSecret   string
+    DatabaseURL     string
+    Port            string
+    JWTSecret       string
+    SupabaseURL     string
+    SupabaseAnonKey string
+    AdminPort       string
 }
 
 func loadConfig() *Config {
     godotenv.Load()
-    
+
     return &Config{
-        DatabaseURL: os.Getenv("DATABASE_URL"),
-        Port:        getEnvOrDefault("PORT", "8080"),
-        JWTSecret:   os.Getenv("JWT_SECRET"),
+        DatabaseURL:     os.Getenv("DATABASE_URL"),
+        Port:            getEnvOrDefault("PORT", "8080"),
+        JWTSecret:       os.Getenv("JWT_SECRET"),
+        SupabaseURL:     os.Getenv("SUPABASE_URL"),
+        SupabaseAnonKey: os.Getenv("SUPABASE_ANON_KEY"),
+        AdminPort:       os.Getenv("ADMIN_PORT"),
     }
 }
 
@@ -40,31 +49,42 @@ func getEnvOrDefault(key, defaultValue string) string {
 }
 
 func main() {
-    cfg := loadConfig()
-    
+    cfg = loadConfig()
+
     // Initialize database connection
-    var err error
-    db, err = pgxpool.New(context.Background(), cfg.DatabaseURL)
+    poolCfg, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+    if err != nil {
+        log.Fatal("Failed to parse database URL:", err)
+    }
+    poolCfg.ConnConfig.Tracer = queryTracer{}
+
+    db, err = pgxpool.NewWithConfig(context.Background(), poolCfg)
     if err != nil {
         log.Fatal("Failed to connect to database:", err)
     }
-    defer db.Close()
-    
+
     // Verify connection
     if err := db.Ping(context.Background()); err != nil {
         log.Fatal("Database ping failed:", err)
     }
-    
+
     log.Println("Connected to TimescaleDB")
-    
+
+    statsCtx, stopStats := context.WithCancel(context.Background())
+    defer stopStats()
+    go pollPoolStats(statsCtx, 15*time.Second)
+
     // Setup routes
     r := mux.NewRouter()
-    
+    r.Use(requestIDMiddleware)
+    r.Use(loggingMiddleware)
+
     // API routes
     api := r.PathPrefix("/api").Subrouter()
-    
+
     // Public endpoints
     api.HandleFunc("/health", healthHandler).Methods("GET")
+    mountMetrics(r, cfg)
     
     // Auth endpoints
     api.HandleFunc("/auth/login", loginHandler).Methods("POST")
@@ -73,24 +93,32 @@ func main() {
     // Protected endpoints (require JWT middleware)
     protected := api.PathPrefix("").Subrouter()
     protected.Use(authMiddleware)
-    
-    // Streams endpoints
-    protected.HandleFunc("/streams/total-daily", getTotalDailyStreams).Methods("GET")
-    protected.HandleFunc("/streams/top-deltas", getTopDeltas).Methods("GET")
-    protected.HandleFunc("/streams/dates", getStreamDates).Methods("GET")
-    
+
+    routeLimits := loadRouteLimits()
+
+    // Streams endpoints - quota shared across the whole /streams/* surface
+    streams := protected.PathPrefix("/streams").Subrouter()
+    streams.Use(rateLimitMiddleware(routeLimits["streams"]))
+    streams.HandleFunc("/query_range", withQueryTimeout(aggregateQueryTimeout, getStreamsQueryRange)).Methods("GET")
+    streams.HandleFunc("/query", withQueryTimeout(aggregateQueryTimeout, getStreamsQuery)).Methods("GET")
+    streams.HandleFunc("/labels", withQueryTimeout(aggregateQueryTimeout, getStreamsLabels)).Methods("GET")
+    streams.HandleFunc("/label/{name}/values", withQueryTimeout(aggregateQueryTimeout, getStreamsLabelValues)).Methods("GET")
+
     // Playlists endpoints
-    protected.HandleFunc("/playlists/list", getPlaylistsList).Methods("GET")
-    protected.HandleFunc("/playlists/{id}/series", getPlaylistSeries).Methods("GET")
-    protected.HandleFunc("/playlists/total-series", getTotalPlaylistSeries).Methods("POST")
-    
+    protected.HandleFunc("/playlists/list", withQueryTimeout(aggregateQueryTimeout, getPlaylistsList)).Methods("GET")
+    protected.HandleFunc("/playlists/{id}/series", withQueryTimeout(aggregateQueryTimeout, getPlaylistSeries)).Methods("GET")
+    protected.HandleFunc("/playlists/total-series", withQueryTimeout(aggregateQueryTimeout, getTotalPlaylistSeries)).Methods("POST")
+
     // Catalogue endpoints
-    protected.HandleFunc("/catalogue/size-series", getCatalogueSizeSeries).Methods("GET")
-    protected.HandleFunc("/catalogue/health-status-heatmap", getHealthStatusHeatmap).Methods("GET")
+    protected.HandleFunc("/catalogue/size-series", withQueryTimeout(aggregateQueryTimeout, getCatalogueSizeSeries)).Methods("GET")
+    protected.Handle("/catalogue/health-status-heatmap",
+        rateLimitMiddleware(routeLimits["catalogue_health_heatmap"])(withQueryTimeout(heatmapQueryTimeout, getHealthStatusHeatmap))).Methods("GET")
     protected.HandleFunc("/catalogue/tracks", manageTracks).Methods("GET", "POST", "PUT", "DELETE")
-    
+    protected.Handle("/catalogue/tracks/bulk",
+        handlerutil.ExemptWriteTimeout(http.HandlerFunc(manageTracksBulk), bulkIngestWriteBudget)).Methods("POST")
+
     // Artists endpoints
-    protected.HandleFunc("/artists/top-share", getTopArtistsShare).Methods("GET")
+    protected.HandleFunc("/artists/top-share", withQueryTimeout(aggregateQueryTimeout, getTopArtistsShare)).Methods("GET")
     
     // CORS
     c := cors.New(cors.Options{
@@ -101,98 +129,22 @@ func main() {
     })
     
     handler := c.Handler(r)
-    
-    log.Printf("Server starting on port %s", cfg.Port)
-    if err := http.ListenAndServe(":"+cfg.Port, handler); err != nil {
-        log.Fatal("Server failed to start:", err)
-    }
+
+    runServer(newServer(cfg, handler))
 }
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
     response := map[string]interface{}{
         "status": "healthy",
         "timestamp": time.Now().Unix(),
-        "database": db.Ping(context.Background()) == nil,
+        "database": db.Ping(r.Context()) == nil,
     }
     
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(response)
 }
 
-func authMiddleware(next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        token := r.Header.Get("Authorization")
-        if token == "" {
-            http.Error(w, "Unauthorized", http.StatusUnauthorized)
-            return
-        }
-        
-        // Validate JWT token (implementation depends on Supabase setup)
-        // For now, pass through
-        next.ServeHTTP(w, r)
-    })
-}
-
 // Placeholder handlers - implementations in separate files
-func loginHandler(w http.ResponseWriter, r *http.Request) {
-    // Implement Supabase auth
-    json.NewEncoder(w).Encode(map[string]string{"status": "not_implemented"})
-}
-
-func refreshHandler(w http.ResponseWriter, r *http.Request) {
-    json.NewEncoder(w).Encode(map[string]string{"status": "not_implemented"})
-}
-
-func getTotalDailyStreams(w http.ResponseWriter, r *http.Request) {
-    userID := getUserIDFromContext(r)
-    days := r.URL.Query().Get("days")
-    if days == "" {
-        days = "90"
-    }
-    
-    query := `
-        SELECT stream_date AS d, SUM(daily_delta)::bigint AS v
-        FROM streams_daily_delta
-        WHERE user_id = $1 
-        AND stream_date >= CURRENT_DATE - CAST($2 AS INTEGER) * INTERVAL '1 day'
-        GROUP BY stream_date 
-        ORDER BY stream_date
-    `
-    
-    rows, err := db.Query(context.Background(), query, userID, days)
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
-        return
-    }
-    defer rows.Close()
-    
-    labels := []string{}
-    values := []int64{}
-    
-    for rows.Next() {
-        var date time.Time
-        var value int64
-        if err := rows.Scan(&date, &value); err != nil {
-            continue
-        }
-        labels = append(labels, date.Format("2006-01-02"))
-        values = append(values, value)
-    }
-    
-    json.NewEncoder(w).Encode(map[string]interface{}{
-        "labels": labels,
-        "values": values,
-    })
-}
-
-func getTopDeltas(w http.ResponseWriter, r *http.Request) {
-    json.NewEncoder(w).Encode(map[string]string{"status": "not_implemented"})
-}
-
-func getStreamDates(w http.ResponseWriter, r *http.Request) {
-    json.NewEncoder(w).Encode(map[string]string{"status": "not_implemented"})
-}
-
 func getPlaylistsList(w http.ResponseWriter, r *http.Request) {
     json.NewEncoder(w).Encode(map[string]string{"status": "not_implemented"})
 }
@@ -220,9 +172,3 @@ func manageTracks(w http.ResponseWriter, r *http.Request) {
 func getTopArtistsShare(w http.ResponseWriter, r *http.Request) {
     json.NewEncoder(w).Encode(map[string]string{"status": "not_implemented"})
 }
-
-func getUserIDFromContext(r *http.Request) string {
-    // Extract user ID from JWT claims
-    // For now return a placeholder
-    return "00000000-0000-0000-0000-000000000000"
-}
@@ -0,0 +1,35 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gorilla/mux"
+)
+
+func TestMountMetricsRegistersOnMainRouterWithoutAdminPort(t *testing.T) {
+    r := mux.NewRouter()
+    mountMetrics(r, &Config{})
+
+    req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+    rec := httptest.NewRecorder()
+    r.ServeHTTP(rec, req)
+
+    if rec.Code != http.StatusOK {
+        t.Errorf("GET /metrics = %d, want 200", rec.Code)
+    }
+}
+
+func TestMountMetricsSkipsMainRouterWithAdminPort(t *testing.T) {
+    r := mux.NewRouter()
+    mountMetrics(r, &Config{AdminPort: "0"})
+
+    req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+    rec := httptest.NewRecorder()
+    r.ServeHTTP(rec, req)
+
+    if rec.Code == http.StatusOK {
+        t.Error("expected /metrics to be absent from the main router when AdminPort is set")
+    }
+}
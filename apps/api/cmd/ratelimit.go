@@ -0,0 +1,103 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "os"
+    "strconv"
+    "sync/atomic"
+    "time"
+
+    "github.com/BrowningL/ISRCAnalytics.com/apps/api/internal/ratelimit"
+    "gopkg.in/yaml.v3"
+)
+
+// defaultRouteLimits are the default per-tenant quotas. They can be overridden by
+// pointing RATE_LIMIT_CONFIG at a YAML file shaped like:
+//
+//	streams:
+//	  burst: 60
+//	  rate: 60
+//	  rate_period: 1m
+//	catalogue_health_heatmap:
+//	  burst: 10
+//	  rate: 10
+//	  rate_period: 1m
+var defaultRouteLimits = map[string]ratelimit.Limit{
+    "streams":                  {Burst: 60, Rate: 60, RatePeriod: time.Minute},
+    "catalogue_health_heatmap": {Burst: 10, Rate: 10, RatePeriod: time.Minute},
+}
+
+type rawLimit struct {
+    Burst      int    `yaml:"burst"`
+    Rate       int    `yaml:"rate"`
+    RatePeriod string `yaml:"rate_period"`
+}
+
+// loadRouteLimits reads RATE_LIMIT_CONFIG if set, falling back to
+// defaultRouteLimits for any key it doesn't override.
+func loadRouteLimits() map[string]ratelimit.Limit {
+    limits := make(map[string]ratelimit.Limit, len(defaultRouteLimits))
+    for k, v := range defaultRouteLimits {
+        limits[k] = v
+    }
+
+    path := os.Getenv("RATE_LIMIT_CONFIG")
+    if path == "" {
+        return limits
+    }
+
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return limits
+    }
+
+    var raw map[string]rawLimit
+    if err := yaml.Unmarshal(data, &raw); err != nil {
+        return limits
+    }
+
+    for k, v := range raw {
+        period, err := time.ParseDuration(v.RatePeriod)
+        if err != nil {
+            continue
+        }
+        limits[k] = ratelimit.Limit{Burst: v.Burst, Rate: v.Rate, RatePeriod: period}
+    }
+    return limits
+}
+
+var rateLimiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+
+// rateLimitScopes numbers each rateLimitMiddleware mount so every mount gets
+// its own bucket per tenant.
+var rateLimitScopes atomic.Int64
+
+// rateLimitMiddleware enforces limit per tenant (the JWT `sub` claim),
+// returning 429 with Retry-After/X-RateLimit-* headers once the bucket is
+// exhausted. Each call keys its buckets by its own scope plus the tenant, so
+// two mounts (e.g. /streams/* and the heatmap) never drain each other.
+func rateLimitMiddleware(limit ratelimit.Limit) func(http.Handler) http.Handler {
+    scope := rateLimitScopes.Add(1)
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            userID, err := getUserIDFromContext(r)
+            if err != nil {
+                http.Error(w, "Unauthorized", http.StatusUnauthorized)
+                return
+            }
+
+            result := rateLimiter.Allow(fmt.Sprintf("%d:%s", scope, userID), limit)
+            w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+            w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+
+            if !result.Allowed {
+                w.Header().Set("Retry-After", fmt.Sprintf("%.0f", result.RetryAfter.Seconds()))
+                http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+                return
+            }
+
+            next.ServeHTTP(w, r)
+        })
+    }
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/BrowningL/ISRCAnalytics.com/apps/api/internal/ratelimit"
+)
+
+func TestLoadRouteLimitsDefaultsWithoutConfig(t *testing.T) {
+    os.Unsetenv("RATE_LIMIT_CONFIG")
+
+    got := loadRouteLimits()
+    if got["streams"] != defaultRouteLimits["streams"] {
+        t.Errorf("streams limit = %+v, want default %+v", got["streams"], defaultRouteLimits["streams"])
+    }
+}
+
+func TestLoadRouteLimitsOverridesFromYAML(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "limits.yaml")
+    yaml := "streams:\n  burst: 5\n  rate: 5\n  rate_period: 30s\n"
+    if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+        t.Fatalf("failed to write test config: %v", err)
+    }
+    os.Setenv("RATE_LIMIT_CONFIG", path)
+    defer os.Unsetenv("RATE_LIMIT_CONFIG")
+
+    got := loadRouteLimits()
+    want := ratelimit.Limit{Burst: 5, Rate: 5, RatePeriod: 30 * time.Second}
+    if got["streams"] != want {
+        t.Errorf("streams limit = %+v, want %+v", got["streams"], want)
+    }
+    if got["catalogue_health_heatmap"] != defaultRouteLimits["catalogue_health_heatmap"] {
+        t.Errorf("unoverridden key should keep its default")
+    }
+}
+
+func TestLoadRouteLimitsIgnoresBadRatePeriod(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "limits.yaml")
+    yaml := "streams:\n  burst: 5\n  rate: 5\n  rate_period: not-a-duration\n"
+    if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+        t.Fatalf("failed to write test config: %v", err)
+    }
+    os.Setenv("RATE_LIMIT_CONFIG", path)
+    defer os.Unsetenv("RATE_LIMIT_CONFIG")
+
+    got := loadRouteLimits()
+    if got["streams"] != defaultRouteLimits["streams"] {
+        t.Errorf("streams limit = %+v, want default kept on parse failure %+v", got["streams"], defaultRouteLimits["streams"])
+    }
+}
+
+func TestRateLimitMiddlewareHeaders(t *testing.T) {
+    cfg = &Config{JWTSecret: testJWTSecret}
+    origLimiter := rateLimiter
+    rateLimiter = ratelimit.NewMemoryLimiter()
+    defer func() { rateLimiter = origLimiter }()
+
+    limit := ratelimit.Limit{Burst: 1, Rate: 1, RatePeriod: time.Minute}
+    mw := rateLimitMiddleware(limit)
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+    req := httptest.NewRequest(http.MethodGet, "/api/streams/query", nil)
+    req = req.WithContext(context.WithValue(req.Context(), claimsContextKey, &Claims{Subject: "tenant-a"}))
+
+    rec := httptest.NewRecorder()
+    mw(next).ServeHTTP(rec, req)
+    if rec.Code != http.StatusOK {
+        t.Fatalf("first request: status = %d, want 200", rec.Code)
+    }
+    if rec.Header().Get("X-RateLimit-Remaining") != "0" {
+        t.Errorf("first request: X-RateLimit-Remaining = %q, want 0", rec.Header().Get("X-RateLimit-Remaining"))
+    }
+
+    rec2 := httptest.NewRecorder()
+    mw(next).ServeHTTP(rec2, req)
+    if rec2.Code != http.StatusTooManyRequests {
+        t.Fatalf("second request: status = %d, want 429", rec2.Code)
+    }
+    if rec2.Header().Get("Retry-After") == "" {
+        t.Error("second request: expected Retry-After header once throttled")
+    }
+}
+
+func TestRateLimitMiddlewareRequiresClaims(t *testing.T) {
+    mw := rateLimitMiddleware(ratelimit.Limit{Burst: 1, Rate: 1, RatePeriod: time.Minute})
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+    rec := httptest.NewRecorder()
+    mw(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/streams/query", nil))
+
+    if rec.Code != http.StatusUnauthorized {
+        t.Errorf("status = %d, want 401 without claims in context", rec.Code)
+    }
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+    "context"
+    "log"
+    "net/http"
+    "os/signal"
+    "syscall"
+    "time"
+)
+
+// newServer builds the *http.Server with slowloris-resistant timeouts.
+// Individual routes that need to stream for longer (bulk ingest, large
+// exports) opt out via handlerutil.ExemptWriteTimeout instead of loosening
+// these globally.
+func newServer(cfg *Config, handler http.Handler) *http.Server {
+    return &http.Server{
+        Addr:              ":" + cfg.Port,
+        Handler:           handler,
+        ReadHeaderTimeout: envDurationOrDefault("READ_HEADER_TIMEOUT", 5*time.Second),
+        ReadTimeout:       envDurationOrDefault("READ_TIMEOUT", 10*time.Second),
+        WriteTimeout:      envDurationOrDefault("WRITE_TIMEOUT", 15*time.Second),
+        IdleTimeout:       envDurationOrDefault("IDLE_TIMEOUT", 60*time.Second),
+    }
+}
+
+// runServer starts srv and blocks until SIGINT/SIGTERM, then drains
+// in-flight requests and closes db before returning.
+func runServer(srv *http.Server) {
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+
+    go func() {
+        log.Printf("Server starting on port %s", srv.Addr)
+        if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+            log.Fatal("Server failed to start:", err)
+        }
+    }()
+
+    <-ctx.Done()
+    log.Println("Shutting down server...")
+
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+    defer cancel()
+
+    if err := srv.Shutdown(shutdownCtx); err != nil {
+        log.Println("Graceful shutdown failed:", err)
+    }
+
+    db.Close()
+    log.Println("Server stopped")
+}
+
+func envDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+    value := getEnvOrDefault(key, "")
+    if value == "" {
+        return defaultValue
+    }
+    d, err := time.ParseDuration(value)
+    if err != nil {
+        log.Printf("invalid duration for %s=%q, using default %s", key, value, defaultValue)
+        return defaultValue
+    }
+    return d
+}
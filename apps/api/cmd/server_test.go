@@ -0,0 +1,43 @@
+package main
+
+import (
+    "os"
+    "testing"
+    "time"
+)
+
+func TestEnvDurationOrDefault(t *testing.T) {
+    const key = "TEST_ENV_DURATION_OR_DEFAULT"
+    os.Unsetenv(key)
+
+    if got := envDurationOrDefault(key, 5*time.Second); got != 5*time.Second {
+        t.Errorf("unset env: got %s, want 5s", got)
+    }
+
+    os.Setenv(key, "not-a-duration")
+    defer os.Unsetenv(key)
+    if got := envDurationOrDefault(key, 5*time.Second); got != 5*time.Second {
+        t.Errorf("invalid env: got %s, want default 5s", got)
+    }
+
+    os.Setenv(key, "30s")
+    if got := envDurationOrDefault(key, 5*time.Second); got != 30*time.Second {
+        t.Errorf("valid env: got %s, want 30s", got)
+    }
+}
+
+func TestNewServerUsesEnvTimeouts(t *testing.T) {
+    os.Setenv("READ_TIMEOUT", "1s")
+    defer os.Unsetenv("READ_TIMEOUT")
+
+    srv := newServer(&Config{Port: "8080"}, nil)
+    if srv.ReadTimeout != time.Second {
+        t.Errorf("ReadTimeout = %s, want 1s from env override", srv.ReadTimeout)
+    }
+    if srv.WriteTimeout != 15*time.Second {
+        t.Errorf("WriteTimeout = %s, want 15s default", srv.WriteTimeout)
+    }
+    if srv.Addr != ":8080" {
+        t.Errorf("Addr = %q, want :8080", srv.Addr)
+    }
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gorilla/mux"
+    "github.com/jackc/pgx/v5"
+)
+
+// streamMetrics whitelists the metric names the query API can expose and the
+// hypertable/column each one reads from. Extend this map, not the SQL below,
+// when a new metric is added.
+var streamMetrics = map[string]struct {
+    table  string
+    column string
+}{
+    "daily_delta": {table: "streams_daily_delta", column: "daily_delta"},
+}
+
+// streamGroupBy whitelists the columns callers may group by so filter/group_by
+// input can never reach the query string unescaped.
+var streamGroupBy = map[string]bool{
+    "isrc":        true,
+    "playlist_id": true,
+    "artist_id":   true,
+}
+
+var stepPattern = regexp.MustCompile(`^(\d+)(h|d)$`)
+
+// parseStep turns a Prometheus-style step ("1h", "7d") into a Postgres
+// interval literal for time_bucket.
+func parseStep(step string) (string, error) {
+    m := stepPattern.FindStringSubmatch(step)
+    if m == nil {
+        return "", fmt.Errorf("invalid step %q, expected e.g. 1h or 7d", step)
+    }
+    unit := map[string]string{"h": "hours", "d": "days"}[m[2]]
+    return fmt.Sprintf("%s %s", m[1], unit), nil
+}
+
+// labelMatcher is a single `label="value"` or `label!="value"` term from the
+// filter query param.
+type labelMatcher struct {
+    label string
+    value string
+    neg   bool
+}
+
+var matcherPattern = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)(!?=)"([^"]*)"$`)
+
+// parseFilter parses a comma-separated list of label matchers, e.g.
+// `isrc="USRC17607839",artist_id!="123"`.
+func parseFilter(filter string) ([]labelMatcher, error) {
+    if filter == "" {
+        return nil, nil
+    }
+    var matchers []labelMatcher
+    for _, term := range strings.Split(filter, ",") {
+        m := matcherPattern.FindStringSubmatch(strings.TrimSpace(term))
+        if m == nil {
+            return nil, fmt.Errorf("invalid filter term %q", term)
+        }
+        if !streamGroupBy[m[1]] {
+            return nil, fmt.Errorf("unsupported filter label %q", m[1])
+        }
+        matchers = append(matchers, labelMatcher{label: m[1], value: m[3], neg: m[2] == "!="})
+    }
+    return matchers, nil
+}
+
+// promSample is a single [timestamp, value] pair in Prometheus's matrix format.
+type promSample struct {
+    ts    int64
+    value int64
+}
+
+func (s promSample) MarshalJSON() ([]byte, error) {
+    return json.Marshal([2]interface{}{s.ts, strconv.FormatInt(s.value, 10)})
+}
+
+type promMatrixResult struct {
+    Metric map[string]string `json:"metric"`
+    Values []promSample      `json:"values"`
+}
+
+// promVectorResult is the instant-query counterpart of promMatrixResult: a
+// single [ts, val] sample instead of a range of them.
+type promVectorResult struct {
+    Metric map[string]string `json:"metric"`
+    Value  promSample        `json:"value"`
+}
+
+type promResponse struct {
+    Status string `json:"status"`
+    Data   struct {
+        ResultType string      `json:"resultType"`
+        Result     interface{} `json:"result"`
+    } `json:"data"`
+}
+
+// getStreamsQueryRange serves GET /api/streams/query_range, the range-vector
+// equivalent of a PromQL query_range call over our TimescaleDB hypertables.
+func getStreamsQueryRange(w http.ResponseWriter, r *http.Request) {
+    userID, err := getUserIDFromContext(r)
+    if err != nil {
+        http.Error(w, "Unauthorized", http.StatusUnauthorized)
+        return
+    }
+
+    q := r.URL.Query()
+    metric, ok := streamMetrics[q.Get("metric")]
+    if !ok {
+        http.Error(w, fmt.Sprintf("unknown metric %q", q.Get("metric")), http.StatusBadRequest)
+        return
+    }
+
+    start, err := time.Parse(time.RFC3339, q.Get("start"))
+    if err != nil {
+        http.Error(w, "invalid start, expected RFC3339", http.StatusBadRequest)
+        return
+    }
+    end, err := time.Parse(time.RFC3339, q.Get("end"))
+    if err != nil {
+        http.Error(w, "invalid end, expected RFC3339", http.StatusBadRequest)
+        return
+    }
+
+    interval, err := parseStep(q.Get("step"))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    groupBy := q.Get("group_by")
+    if groupBy != "" && !streamGroupBy[groupBy] {
+        http.Error(w, fmt.Sprintf("unsupported group_by %q", groupBy), http.StatusBadRequest)
+        return
+    }
+
+    matchers, err := parseFilter(q.Get("filter"))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    args := []interface{}{userID, start, end}
+    var where strings.Builder
+    for _, m := range matchers {
+        args = append(args, m.value)
+        op := "="
+        if m.neg {
+            op = "!="
+        }
+        fmt.Fprintf(&where, " AND %s %s $%d", m.label, op, len(args))
+    }
+
+    groupCols := "1"
+    selectGroup := ""
+    if groupBy != "" {
+        selectGroup = groupBy + ","
+        groupCols = "1, 2"
+    }
+
+    query := fmt.Sprintf(`
+        SELECT time_bucket('%s', stream_date) AS bucket, %s SUM(%s)::bigint AS v
+        FROM %s
+        WHERE user_id = $1 AND stream_date >= $2 AND stream_date <= $3 %s
+        GROUP BY %s
+        ORDER BY bucket
+    `, interval, selectGroup, metric.column, metric.table, where.String(), groupCols)
+
+    rows, err := db.Query(r.Context(), query, args...)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    series := map[string]*promMatrixResult{}
+    var order []string
+    for rows.Next() {
+        var bucket time.Time
+        var groupVal string
+        var value int64
+        if groupBy != "" {
+            if err := rows.Scan(&bucket, &groupVal, &value); err != nil {
+                continue
+            }
+        } else {
+            if err := rows.Scan(&bucket, &value); err != nil {
+                continue
+            }
+        }
+
+        key := groupVal
+        result, ok := series[key]
+        if !ok {
+            labels := map[string]string{"__name__": q.Get("metric")}
+            if groupBy != "" {
+                labels[groupBy] = groupVal
+            }
+            result = &promMatrixResult{Metric: labels}
+            series[key] = result
+            order = append(order, key)
+        }
+        result.Values = append(result.Values, promSample{ts: bucket.Unix(), value: value})
+    }
+
+    matrixResult := make([]promMatrixResult, 0, len(order))
+    for _, key := range order {
+        matrixResult = append(matrixResult, *series[key])
+    }
+
+    resp := promResponse{Status: "success"}
+    resp.Data.ResultType = "matrix"
+    resp.Data.Result = matrixResult
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(resp)
+}
+
+// getStreamsQuery serves GET /api/streams/query, an instant-vector query
+// returning today's summed value (stream_date = CURRENT_DATE) instead of the
+// full range. The result is empty when there are no rows for today; earlier
+// dates are not consulted.
+func getStreamsQuery(w http.ResponseWriter, r *http.Request) {
+    userID, err := getUserIDFromContext(r)
+    if err != nil {
+        http.Error(w, "Unauthorized", http.StatusUnauthorized)
+        return
+    }
+
+    q := r.URL.Query()
+    metric, ok := streamMetrics[q.Get("metric")]
+    if !ok {
+        http.Error(w, fmt.Sprintf("unknown metric %q", q.Get("metric")), http.StatusBadRequest)
+        return
+    }
+
+    matchers, err := parseFilter(q.Get("filter"))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    args := []interface{}{userID}
+    var where strings.Builder
+    for _, m := range matchers {
+        args = append(args, m.value)
+        op := "="
+        if m.neg {
+            op = "!="
+        }
+        fmt.Fprintf(&where, " AND %s %s $%d", m.label, op, len(args))
+    }
+
+    query := fmt.Sprintf(`
+        SELECT stream_date, SUM(%s)::bigint AS v
+        FROM %s
+        WHERE user_id = $1 AND stream_date = CURRENT_DATE %s
+        GROUP BY stream_date
+    `, metric.column, metric.table, where.String())
+
+    var bucket time.Time
+    var value int64
+    err = db.QueryRow(r.Context(), query, args...).Scan(&bucket, &value)
+    switch {
+    case err == nil:
+        // handled below
+    case errors.Is(err, pgx.ErrNoRows):
+        // no rows for today is a valid empty result, not a failure
+    default:
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    resp := promResponse{Status: "success"}
+    resp.Data.ResultType = "vector"
+    if err == nil {
+        resp.Data.Result = []promVectorResult{{
+            Metric: map[string]string{"__name__": q.Get("metric")},
+            Value:  promSample{ts: bucket.Unix(), value: value},
+        }}
+    } else {
+        resp.Data.Result = []promVectorResult{}
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(resp)
+}
+
+// getStreamsLabels serves GET /api/streams/labels, listing the label names
+// available for group_by/filter.
+func getStreamsLabels(w http.ResponseWriter, r *http.Request) {
+    labels := make([]string, 0, len(streamGroupBy))
+    for label := range streamGroupBy {
+        labels = append(labels, label)
+    }
+    json.NewEncoder(w).Encode(map[string]interface{}{
+        "status": "success",
+        "data":   labels,
+    })
+}
+
+// getStreamsLabelValues serves GET /api/streams/label/{name}/values, listing
+// the distinct values seen for a given label within the tenant's data.
+func getStreamsLabelValues(w http.ResponseWriter, r *http.Request) {
+    userID, err := getUserIDFromContext(r)
+    if err != nil {
+        http.Error(w, "Unauthorized", http.StatusUnauthorized)
+        return
+    }
+
+    label := mux.Vars(r)["name"]
+    if !streamGroupBy[label] {
+        http.Error(w, fmt.Sprintf("unsupported label %q", label), http.StatusBadRequest)
+        return
+    }
+
+    query := fmt.Sprintf(`SELECT DISTINCT %s FROM streams_daily_delta WHERE user_id = $1 ORDER BY 1 LIMIT 1000`, label)
+    rows, err := db.Query(r.Context(), query, userID)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    values := []string{}
+    for rows.Next() {
+        var v string
+        if err := rows.Scan(&v); err != nil {
+            continue
+        }
+        values = append(values, v)
+    }
+
+    json.NewEncoder(w).Encode(map[string]interface{}{
+        "status": "success",
+        "data":   values,
+    })
+}
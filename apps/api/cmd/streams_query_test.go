@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestParseStep(t *testing.T) {
+    cases := []struct {
+        in      string
+        want    string
+        wantErr bool
+    }{
+        {in: "1h", want: "1 hours"},
+        {in: "7d", want: "7 days"},
+        {in: "30m", wantErr: true},
+        {in: "", wantErr: true},
+    }
+
+    for _, c := range cases {
+        got, err := parseStep(c.in)
+        if c.wantErr {
+            if err == nil {
+                t.Errorf("parseStep(%q): expected error, got %q", c.in, got)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("parseStep(%q): unexpected error: %v", c.in, err)
+            continue
+        }
+        if got != c.want {
+            t.Errorf("parseStep(%q) = %q, want %q", c.in, got, c.want)
+        }
+    }
+}
+
+func TestParseFilter(t *testing.T) {
+    matchers, err := parseFilter(`isrc="USRC17607839",artist_id!="123"`)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if len(matchers) != 2 {
+        t.Fatalf("expected 2 matchers, got %d", len(matchers))
+    }
+    if matchers[0] != (labelMatcher{label: "isrc", value: "USRC17607839", neg: false}) {
+        t.Errorf("unexpected first matcher: %+v", matchers[0])
+    }
+    if matchers[1] != (labelMatcher{label: "artist_id", value: "123", neg: true}) {
+        t.Errorf("unexpected second matcher: %+v", matchers[1])
+    }
+}
+
+func TestParseFilterEmpty(t *testing.T) {
+    matchers, err := parseFilter("")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if matchers != nil {
+        t.Errorf("expected nil matchers for empty filter, got %+v", matchers)
+    }
+}
+
+func TestParseFilterRejectsUnknownLabel(t *testing.T) {
+    if _, err := parseFilter(`secret_column="x"`); err == nil {
+        t.Error("expected error for non-whitelisted label")
+    }
+}
+
+func TestParseFilterRejectsMalformedTerm(t *testing.T) {
+    if _, err := parseFilter(`isrc=USRC17607839`); err == nil {
+        t.Error("expected error for term missing quotes")
+    }
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "time"
+)
+
+// withQueryTimeout wraps next so every downstream DB call made from r.Context()
+// is cancelled once budget elapses or the client disconnects, whichever comes
+// first. Mount per-route with a budget matched to what the query actually
+// needs (aggregates are cheap, heatmaps scan a lot more rows).
+func withQueryTimeout(budget time.Duration, next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        ctx, cancel := context.WithTimeout(r.Context(), budget)
+        defer cancel()
+        next(w, r.WithContext(ctx))
+    }
+}
+
+const (
+    aggregateQueryTimeout = 10 * time.Second
+    heatmapQueryTimeout   = 30 * time.Second
+)
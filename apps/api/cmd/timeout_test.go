@@ -0,0 +1,42 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+func TestWithQueryTimeoutAppliesDeadline(t *testing.T) {
+    var gotDeadline time.Time
+    var hasDeadline bool
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        gotDeadline, hasDeadline = r.Context().Deadline()
+    })
+
+    before := time.Now()
+    withQueryTimeout(5*time.Second, next)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+    after := time.Now()
+
+    if !hasDeadline {
+        t.Fatal("expected request context to carry a deadline")
+    }
+    if gotDeadline.Before(before.Add(5*time.Second)) || gotDeadline.After(after.Add(5*time.Second)) {
+        t.Errorf("deadline %s not within budget window", gotDeadline)
+    }
+}
+
+func TestWithQueryTimeoutCancelsOnExpiry(t *testing.T) {
+    var ctxErr error
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        <-r.Context().Done()
+        ctxErr = r.Context().Err()
+    })
+
+    withQueryTimeout(time.Millisecond, next)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+    if ctxErr != context.DeadlineExceeded {
+        t.Errorf("ctx.Err() = %v, want context.DeadlineExceeded", ctxErr)
+    }
+}
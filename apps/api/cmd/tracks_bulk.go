@@ -0,0 +1,360 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/jackc/pgx/v5"
+)
+
+// bulkIngestWriteBudget is how long the streaming bulk-ingest response is
+// allowed to take end to end; the route is exempted from the server's global
+// WriteTimeout via handlerutil since tens of thousands of rows can take a
+// while to COPY in.
+const bulkIngestWriteBudget = 10 * time.Minute
+
+const trackBatchSize = 5000
+
+// trackRow is one row of the bulk ingest payload, whether it arrived as CSV
+// or NDJSON.
+type trackRow struct {
+    ISRC        string `json:"isrc"`
+    Title       string `json:"title"`
+    Artist      string `json:"artist"`
+    Album       string `json:"album"`
+    ReleaseDate string `json:"release_date"`
+    DurationMS  int    `json:"duration_ms"`
+    Label       string `json:"label"`
+}
+
+type rowResult struct {
+    Line   int    `json:"line"`
+    Status string `json:"status"`
+    Error  string `json:"error,omitempty"`
+}
+
+// numberedTrackRow carries the originating line number alongside a parsed
+// row so batch results can report the line they came from, not just a batch
+// index.
+type numberedTrackRow struct {
+    trackRow
+    line int
+}
+
+// parsedItem is what streamCSVRows/streamNDJSONRows send on their single
+// output channel: either a successfully parsed row or a parse error, tagged
+// with the line it came from. Carrying both on one channel (instead of a
+// separate rows/errs pair) means the consumer can never stall on one while
+// the other backs up.
+type parsedItem struct {
+    row  trackRow
+    line int
+    err  string
+}
+
+func (row trackRow) validate() error {
+    if row.ISRC == "" {
+        return fmt.Errorf("isrc is required")
+    }
+    if row.Title == "" {
+        return fmt.Errorf("title is required")
+    }
+    if _, err := time.Parse("2006-01-02", row.ReleaseDate); row.ReleaseDate != "" && err != nil {
+        return fmt.Errorf("release_date must be YYYY-MM-DD")
+    }
+    return nil
+}
+
+// manageTracksBulk serves POST /api/catalogue/tracks/bulk. It streams either
+// CSV or NDJSON, validates each row, and COPYs valid rows into the tracks
+// table in batches of trackBatchSize so a 50k-row distributor catalogue
+// doesn't need to be buffered in memory or inserted one statement at a time.
+func manageTracksBulk(w http.ResponseWriter, r *http.Request) {
+    userID, err := getUserIDFromContext(r)
+    if err != nil {
+        http.Error(w, "Unauthorized", http.StatusUnauthorized)
+        return
+    }
+
+    idempotencyKey := r.Header.Get("Idempotency-Key")
+    if idempotencyKey != "" {
+        claimed, err := claimIdempotencyKey(r.Context(), userID, idempotencyKey)
+        if err != nil {
+            http.Error(w, "failed to check idempotency key", http.StatusInternalServerError)
+            return
+        }
+        if !claimed {
+            http.Error(w, "this idempotency key has already been used", http.StatusConflict)
+            return
+        }
+    }
+
+    var items <-chan parsedItem
+    switch contentType := r.Header.Get("Content-Type"); {
+    case strings.HasPrefix(contentType, "text/csv"):
+        items = streamCSVRows(r.Body)
+    case strings.HasPrefix(contentType, "application/x-ndjson"):
+        items = streamNDJSONRows(r.Body)
+    default:
+        if idempotencyKey != "" {
+            releaseIdempotencyKey(r.Context(), userID, idempotencyKey)
+        }
+        http.Error(w, "Content-Type must be text/csv or application/x-ndjson", http.StatusUnsupportedMediaType)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/x-ndjson")
+    w.WriteHeader(http.StatusOK)
+    enc := json.NewEncoder(w)
+    flusher, _ := w.(http.Flusher)
+
+    anyBatchCommitted := false
+    batch := make([]numberedTrackRow, 0, trackBatchSize)
+    flushBatch := func() {
+        if len(batch) == 0 {
+            return
+        }
+        results := insertTrackBatch(r.Context(), userID, batch)
+        for _, res := range results {
+            enc.Encode(res)
+            if res.Status == "ok" {
+                anyBatchCommitted = true
+            }
+        }
+        if flusher != nil {
+            flusher.Flush()
+        }
+        batch = batch[:0]
+    }
+
+    for item := range items {
+        if item.err != "" {
+            enc.Encode(rowResult{Line: item.line, Status: "error", Error: item.err})
+            if flusher != nil {
+                flusher.Flush()
+            }
+            continue
+        }
+        if err := item.row.validate(); err != nil {
+            enc.Encode(rowResult{Line: item.line, Status: "error", Error: err.Error()})
+            if flusher != nil {
+                flusher.Flush()
+            }
+            continue
+        }
+        batch = append(batch, numberedTrackRow{trackRow: item.row, line: item.line})
+        if len(batch) >= trackBatchSize {
+            flushBatch()
+        }
+    }
+    flushBatch()
+
+    // The key was already claimed atomically above. Release it if nothing
+    // actually committed (e.g. the DB connection dropped mid-request) so a
+    // retry isn't rejected forever despite zero rows having landed.
+    if idempotencyKey != "" && !anyBatchCommitted {
+        releaseIdempotencyKey(r.Context(), userID, idempotencyKey)
+    }
+}
+
+// streamCSVRows parses CSV without buffering the whole body in memory,
+// sending both rows and parse errors on the single returned channel so the
+// consumer can drain it with a plain range loop without risking a deadlock
+// between two separately-buffered channels.
+func streamCSVRows(body io.ReadCloser) <-chan parsedItem {
+    out := make(chan parsedItem)
+
+    go func() {
+        defer close(out)
+        defer body.Close()
+
+        reader := csv.NewReader(bufio.NewReader(body))
+        header, err := reader.Read()
+        if err != nil {
+            out <- parsedItem{line: 0, err: "failed to read CSV header: " + err.Error()}
+            return
+        }
+        colIndex := make(map[string]int, len(header))
+        for i, col := range header {
+            colIndex[strings.TrimSpace(col)] = i
+        }
+
+        line := 0
+        for {
+            record, err := reader.Read()
+            if err == io.EOF {
+                return
+            }
+            line++
+            if err != nil {
+                out <- parsedItem{line: line, err: "failed to read CSV row: " + err.Error()}
+                return
+            }
+            out <- parsedItem{row: csvRecordToTrackRow(record, colIndex), line: line}
+        }
+    }()
+
+    return out
+}
+
+func csvRecordToTrackRow(record []string, colIndex map[string]int) trackRow {
+    get := func(col string) string {
+        if i, ok := colIndex[col]; ok && i < len(record) {
+            return record[i]
+        }
+        return ""
+    }
+    duration, _ := strconv.Atoi(get("duration_ms"))
+    return trackRow{
+        ISRC:        get("isrc"),
+        Title:       get("title"),
+        Artist:      get("artist"),
+        Album:       get("album"),
+        ReleaseDate: get("release_date"),
+        DurationMS:  duration,
+        Label:       get("label"),
+    }
+}
+
+// streamNDJSONRows parses newline-delimited JSON without buffering the whole
+// body in memory, sending both rows and parse errors on the single returned
+// channel (see streamCSVRows).
+func streamNDJSONRows(body io.ReadCloser) <-chan parsedItem {
+    out := make(chan parsedItem)
+
+    go func() {
+        defer close(out)
+        defer body.Close()
+
+        scanner := bufio.NewScanner(body)
+        scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+        line := 0
+        for scanner.Scan() {
+            line++
+            text := strings.TrimSpace(scanner.Text())
+            if text == "" {
+                continue
+            }
+            var row trackRow
+            if err := json.Unmarshal([]byte(text), &row); err != nil {
+                out <- parsedItem{line: line, err: "invalid JSON: " + err.Error()}
+                continue
+            }
+            out <- parsedItem{row: row, line: line}
+        }
+    }()
+
+    return out
+}
+
+// insertTrackBatch COPYs a validated batch into the tracks table inside a
+// single transaction, stamping user_id from the JWT regardless of what the
+// payload contains so one tenant can never write into another's catalogue.
+// If the COPY fails - a duplicate ISRC, a constraint violation, anything -
+// it falls back to inserting the batch one row at a time so a single bad
+// row can't collaterally fail the up-to-trackBatchSize good rows next to
+// it.
+func insertTrackBatch(ctx context.Context, userID string, rows []numberedTrackRow) []rowResult {
+    if results, ok := copyTrackBatch(ctx, userID, rows); ok {
+        return results
+    }
+    return insertTracksOneByOne(ctx, userID, rows)
+}
+
+func copyTrackBatch(ctx context.Context, userID string, rows []numberedTrackRow) ([]rowResult, bool) {
+    tx, err := db.Begin(ctx)
+    if err != nil {
+        return nil, false
+    }
+    defer tx.Rollback(ctx)
+
+    copySource := make([][]interface{}, len(rows))
+    for i, row := range rows {
+        copySource[i] = []interface{}{
+            userID, row.ISRC, row.Title, row.Artist, row.Album, releaseDateParam(row.ReleaseDate), row.DurationMS, row.Label,
+        }
+    }
+
+    if _, err := tx.CopyFrom(
+        ctx,
+        pgx.Identifier{"tracks"},
+        []string{"user_id", "isrc", "title", "artist", "album", "release_date", "duration_ms", "label"},
+        pgx.CopyFromRows(copySource),
+    ); err != nil {
+        return nil, false
+    }
+
+    if err := tx.Commit(ctx); err != nil {
+        return nil, false
+    }
+
+    results := make([]rowResult, len(rows))
+    for i, row := range rows {
+        results[i] = rowResult{Line: row.line, Status: "ok"}
+    }
+    return results, true
+}
+
+// insertTracksOneByOne is the fallback path for a batch whose COPY failed:
+// each row gets its own INSERT and its own result, so one bad ISRC or
+// constraint violation only fails that row instead of every row in the
+// batch it happened to land in.
+func insertTracksOneByOne(ctx context.Context, userID string, rows []numberedTrackRow) []rowResult {
+    results := make([]rowResult, 0, len(rows))
+    for _, row := range rows {
+        _, err := db.Exec(ctx,
+            `INSERT INTO tracks (user_id, isrc, title, artist, album, release_date, duration_ms, label) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+            userID, row.ISRC, row.Title, row.Artist, row.Album, releaseDateParam(row.ReleaseDate), row.DurationMS, row.Label,
+        )
+        if err != nil {
+            results = append(results, rowResult{Line: row.line, Status: "error", Error: "insert failed: " + err.Error()})
+            continue
+        }
+        results = append(results, rowResult{Line: row.line, Status: "ok"})
+    }
+    return results
+}
+
+// releaseDateParam maps an unset release_date to NULL instead of an empty
+// string, since release_date is optional per trackRow.validate() but pgx's
+// date codec has no encode plan for a bare "".
+func releaseDateParam(releaseDate string) interface{} {
+    if releaseDate == "" {
+        return nil
+    }
+    return releaseDate
+}
+
+// claimIdempotencyKey atomically claims (userID, key) using the table's
+// uniqueness constraint, so two concurrent retries with the same
+// Idempotency-Key can't both pass a check-then-insert race - at most one
+// claims the key, the other is told it's already been used. The claim is
+// provisional: releaseIdempotencyKey backs it out if the ingest as a whole
+// commits nothing, so a retry after a total failure isn't locked out
+// forever.
+func claimIdempotencyKey(ctx context.Context, userID, key string) (bool, error) {
+    var claimed int
+    err := db.QueryRow(ctx,
+        `INSERT INTO ingest_idempotency_keys (user_id, key, created_at) VALUES ($1, $2, now()) ON CONFLICT DO NOTHING RETURNING 1`,
+        userID, key).Scan(&claimed)
+    if errors.Is(err, pgx.ErrNoRows) {
+        return false, nil
+    }
+    if err != nil {
+        return false, err
+    }
+    return true, nil
+}
+
+func releaseIdempotencyKey(ctx context.Context, userID, key string) {
+    db.Exec(ctx, `DELETE FROM ingest_idempotency_keys WHERE user_id = $1 AND key = $2`, userID, key)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+    "io"
+    "strings"
+    "testing"
+)
+
+func TestStreamCSVRows(t *testing.T) {
+    body := io.NopCloser(strings.NewReader(
+        "isrc,title,artist,album,release_date,duration_ms,label\n" +
+            "USRC17607839,Song A,Artist A,Album A,2020-01-01,180000,Label A\n" +
+            "USRC17607840,Song B,Artist B,Album B,2021-02-02,200000,Label B\n",
+    ))
+
+    var got []parsedItem
+    for item := range streamCSVRows(body) {
+        got = append(got, item)
+    }
+
+    if len(got) != 2 {
+        t.Fatalf("expected 2 items, got %d", len(got))
+    }
+    if got[0].err != "" || got[0].row.ISRC != "USRC17607839" {
+        t.Errorf("row 1: unexpected item %+v", got[0])
+    }
+    if got[1].err != "" || got[1].row.ISRC != "USRC17607840" {
+        t.Errorf("row 2: unexpected item %+v", got[1])
+    }
+}
+
+func TestStreamCSVRowsMismatchedFieldCount(t *testing.T) {
+    body := io.NopCloser(strings.NewReader(
+        "isrc,title,artist,album,release_date,duration_ms,label\n" +
+            "not,enough,columns\n",
+    ))
+
+    var got []parsedItem
+    for item := range streamCSVRows(body) {
+        got = append(got, item)
+    }
+
+    if len(got) != 1 || got[0].err == "" {
+        t.Fatalf("expected a single row-error item, got %+v", got)
+    }
+}
+
+func TestStreamCSVRowsBadHeader(t *testing.T) {
+    body := io.NopCloser(strings.NewReader(""))
+
+    var got []parsedItem
+    for item := range streamCSVRows(body) {
+        got = append(got, item)
+    }
+
+    if len(got) != 1 || got[0].err == "" {
+        t.Fatalf("expected a single header-error item, got %+v", got)
+    }
+}
+
+func TestStreamNDJSONRows(t *testing.T) {
+    body := io.NopCloser(strings.NewReader(
+        `{"isrc":"USRC17607839","title":"Song A"}` + "\n" +
+            `not json` + "\n" +
+            "\n" +
+            `{"isrc":"USRC17607840","title":"Song B"}` + "\n",
+    ))
+
+    var got []parsedItem
+    for item := range streamNDJSONRows(body) {
+        got = append(got, item)
+    }
+
+    if len(got) != 3 {
+        t.Fatalf("expected 3 items (blank line skipped), got %d", len(got))
+    }
+    if got[0].err != "" || got[0].row.ISRC != "USRC17607839" {
+        t.Errorf("item 1: unexpected item %+v", got[0])
+    }
+    if got[1].err == "" {
+        t.Errorf("item 2: expected a parse error for invalid JSON, got %+v", got[1])
+    }
+    if got[2].err != "" || got[2].row.ISRC != "USRC17607840" {
+        t.Errorf("item 3: unexpected item %+v", got[2])
+    }
+}
+
+func TestTrackRowValidate(t *testing.T) {
+    cases := []struct {
+        name    string
+        row     trackRow
+        wantErr bool
+    }{
+        {name: "valid", row: trackRow{ISRC: "USRC17607839", Title: "Song A"}},
+        {name: "missing isrc", row: trackRow{Title: "Song A"}, wantErr: true},
+        {name: "missing title", row: trackRow{ISRC: "USRC17607839"}, wantErr: true},
+        {name: "bad release_date", row: trackRow{ISRC: "USRC17607839", Title: "Song A", ReleaseDate: "01/01/2020"}, wantErr: true},
+    }
+
+    for _, c := range cases {
+        err := c.row.validate()
+        if c.wantErr && err == nil {
+            t.Errorf("%s: expected error, got nil", c.name)
+        }
+        if !c.wantErr && err != nil {
+            t.Errorf("%s: unexpected error: %v", c.name, err)
+        }
+    }
+}
+
+func TestReleaseDateParam(t *testing.T) {
+    if got := releaseDateParam(""); got != nil {
+        t.Errorf("empty release_date: got %v, want nil", got)
+    }
+    if got := releaseDateParam("2020-01-01"); got != "2020-01-01" {
+        t.Errorf("set release_date: got %v, want %q", got, "2020-01-01")
+    }
+}
@@ -0,0 +1,29 @@
+// Package handlerutil holds small net/http helpers shared across route
+// registrations that don't fit the global server timeouts.
+package handlerutil
+
+import (
+    "net/http"
+    "time"
+)
+
+// ExemptWriteTimeout wraps a handler so it resets the underlying connection's
+// read and write deadlines before serving, letting long-running streaming
+// endpoints (CSV/NDJSON exports, bulk ingest) bypass the global
+// http.Server.ReadTimeout/WriteTimeout. Resetting only the write deadline
+// isn't enough for uploads: the global ReadTimeout would still cut off a
+// slow client mid-body on a large bulk-ingest POST. Mount it per-route;
+// everything else stays bounded by the server defaults.
+func ExemptWriteTimeout(next http.Handler, budget time.Duration) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        rc := http.NewResponseController(w)
+        if budget > 0 {
+            rc.SetReadDeadline(time.Now().Add(budget))
+            rc.SetWriteDeadline(time.Now().Add(budget))
+        } else {
+            rc.SetReadDeadline(time.Time{})
+            rc.SetWriteDeadline(time.Time{})
+        }
+        next.ServeHTTP(w, r)
+    })
+}
@@ -0,0 +1,61 @@
+package handlerutil
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+// deadlineRecorder is a minimal http.ResponseWriter that also implements the
+// optional SetReadDeadline/SetWriteDeadline methods http.ResponseController
+// looks for, so we can assert on what ExemptWriteTimeout actually sets.
+type deadlineRecorder struct {
+    *httptest.ResponseRecorder
+    readDeadline  time.Time
+    writeDeadline time.Time
+}
+
+func (d *deadlineRecorder) SetReadDeadline(deadline time.Time) error {
+    d.readDeadline = deadline
+    return nil
+}
+
+func (d *deadlineRecorder) SetWriteDeadline(deadline time.Time) error {
+    d.writeDeadline = deadline
+    return nil
+}
+
+func TestExemptWriteTimeoutSetsBudgetDeadline(t *testing.T) {
+    rec := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+    var called bool
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+    before := time.Now()
+    ExemptWriteTimeout(next, time.Minute).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+    after := time.Now()
+
+    if !called {
+        t.Fatal("expected wrapped handler to run")
+    }
+    if rec.readDeadline.Before(before.Add(time.Minute)) || rec.readDeadline.After(after.Add(time.Minute)) {
+        t.Errorf("read deadline %s not within budget window", rec.readDeadline)
+    }
+    if rec.writeDeadline.Before(before.Add(time.Minute)) || rec.writeDeadline.After(after.Add(time.Minute)) {
+        t.Errorf("write deadline %s not within budget window", rec.writeDeadline)
+    }
+}
+
+func TestExemptWriteTimeoutZeroBudgetClearsDeadline(t *testing.T) {
+    rec := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+    ExemptWriteTimeout(next, 0).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+    if !rec.readDeadline.IsZero() {
+        t.Errorf("expected zero read deadline for budget<=0, got %s", rec.readDeadline)
+    }
+    if !rec.writeDeadline.IsZero() {
+        t.Errorf("expected zero write deadline for budget<=0, got %s", rec.writeDeadline)
+    }
+}
@@ -0,0 +1,90 @@
+// Package ratelimit provides a per-tenant request quota abstraction. The
+// in-memory implementation here is fine for a single instance; a
+// Redis-backed Limiter can implement the same interface for multi-instance
+// deployments without touching call sites.
+package ratelimit
+
+import (
+    "sync"
+    "time"
+)
+
+// Limit describes a token-bucket quota: Burst tokens refilling at Rate
+// tokens per RatePeriod.
+type Limit struct {
+    Burst      int
+    Rate       int
+    RatePeriod time.Duration
+}
+
+// Result reports the outcome of a single Allow call.
+type Result struct {
+    Allowed    bool
+    Remaining  int
+    ResetAt    time.Time
+    RetryAfter time.Duration
+}
+
+// Limiter enforces per-key request quotas. Key is typically the JWT `sub`
+// claim so quotas are per tenant regardless of which instance serves them.
+type Limiter interface {
+    Allow(key string, limit Limit) Result
+}
+
+// bucket is a single token bucket, refilled lazily on Allow.
+type bucket struct {
+    tokens     float64
+    lastRefill time.Time
+}
+
+// MemoryLimiter is an in-process token-bucket Limiter. It does not
+// coordinate across instances; swap in a Redis-backed Limiter for that.
+type MemoryLimiter struct {
+    mu      sync.Mutex
+    buckets map[string]*bucket
+}
+
+// NewMemoryLimiter creates an empty in-memory limiter.
+func NewMemoryLimiter() *MemoryLimiter {
+    return &MemoryLimiter{buckets: make(map[string]*bucket)}
+}
+
+// Allow consumes one token for key under limit, refilling based on elapsed
+// time since the bucket was last touched.
+func (m *MemoryLimiter) Allow(key string, limit Limit) Result {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    now := time.Now()
+    b, ok := m.buckets[key]
+    if !ok {
+        b = &bucket{tokens: float64(limit.Burst), lastRefill: now}
+        m.buckets[key] = b
+    }
+
+    elapsed := now.Sub(b.lastRefill)
+    refillRate := float64(limit.Rate) / limit.RatePeriod.Seconds()
+    b.tokens += elapsed.Seconds() * refillRate
+    if b.tokens > float64(limit.Burst) {
+        b.tokens = float64(limit.Burst)
+    }
+    b.lastRefill = now
+
+    if b.tokens < 1 {
+        deficit := 1 - b.tokens
+        retryAfter := time.Duration((deficit / refillRate) * float64(time.Second))
+        return Result{
+            Allowed:    false,
+            Remaining:  0,
+            ResetAt:    now.Add(retryAfter),
+            RetryAfter: retryAfter,
+        }
+    }
+
+    b.tokens--
+    return Result{
+        Allowed:   true,
+        Remaining: int(b.tokens),
+        ResetAt:   now.Add(limit.RatePeriod),
+    }
+}
@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+    "testing"
+    "time"
+)
+
+func TestMemoryLimiterAllowsUpToBurst(t *testing.T) {
+    m := NewMemoryLimiter()
+    limit := Limit{Burst: 3, Rate: 3, RatePeriod: time.Minute}
+
+    for i := 0; i < 3; i++ {
+        if res := m.Allow("tenant", limit); !res.Allowed {
+            t.Fatalf("request %d: expected allowed, got denied", i)
+        }
+    }
+
+    if res := m.Allow("tenant", limit); res.Allowed {
+        t.Fatal("expected 4th request within the same burst window to be denied")
+    }
+}
+
+func TestMemoryLimiterRetryAfterIsInSeconds(t *testing.T) {
+    m := NewMemoryLimiter()
+    // 1 token/min refill rate, burst of 1, so a denied request should
+    // report a RetryAfter on the order of a minute, not near-zero.
+    limit := Limit{Burst: 1, Rate: 1, RatePeriod: time.Minute}
+
+    m.Allow("tenant", limit)
+    res := m.Allow("tenant", limit)
+    if res.Allowed {
+        t.Fatal("expected second request to be denied")
+    }
+
+    if res.RetryAfter < 30*time.Second || res.RetryAfter > time.Minute {
+        t.Fatalf("expected RetryAfter near 1m, got %s", res.RetryAfter)
+    }
+}
+
+func TestMemoryLimiterPerKeyIsolation(t *testing.T) {
+    m := NewMemoryLimiter()
+    limit := Limit{Burst: 1, Rate: 1, RatePeriod: time.Minute}
+
+    m.Allow("tenant-a", limit)
+    if res := m.Allow("tenant-a", limit); res.Allowed {
+        t.Fatal("expected tenant-a to be throttled after exhausting its burst")
+    }
+    if res := m.Allow("tenant-b", limit); !res.Allowed {
+        t.Fatal("expected tenant-b's quota to be unaffected by tenant-a")
+    }
+}